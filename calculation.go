@@ -0,0 +1,324 @@
+package salestax
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TaxBehavior specifies whether a line item's Amount already includes tax.
+type TaxBehavior string
+
+const (
+	// TaxBehaviorInclusive means the line item Amount already includes tax.
+	TaxBehaviorInclusive TaxBehavior = "inclusive"
+	// TaxBehaviorExclusive means tax must be added on top of the line item Amount.
+	TaxBehaviorExclusive TaxBehavior = "exclusive"
+)
+
+// Address is a postal address used to resolve the applicable tax jurisdiction.
+type Address struct {
+	// Country is the two-letter ISO country code of the address.
+	Country string
+	// State is the optional state/province/region code of the address.
+	State *string
+	// PostalCode is the optional postal code of the address.
+	PostalCode *string
+}
+
+// TaxID is a customer-supplied VAT/GST registration number.
+type TaxID struct {
+	// Type identifies the kind of tax ID, e.g. "eu_vat" or "gb_vat".
+	Type string
+	// Value is the tax ID number as supplied by the customer.
+	Value string
+}
+
+// CustomerDetails describes the party a TaxCalculation is being computed for.
+type CustomerDetails struct {
+	// Address is the customer's address.
+	Address Address
+	// TaxIDs are the VAT/GST numbers the customer has declared.
+	TaxIDs []TaxID
+}
+
+// LineItem is a single priced item within a TaxCalculationInput.
+type LineItem struct {
+	// Reference is the caller-supplied identifier for this line, echoed back on its LineItemTax.
+	Reference string
+	// Amount is the unit price in the smallest currency unit (e.g. cents).
+	Amount int64
+	// Quantity is the number of units being purchased.
+	Quantity int64
+	// TaxBehavior specifies whether Amount already includes tax.
+	TaxBehavior TaxBehavior
+	// TaxCode is the ProductClass to rate this item under, e.g. "reduced" or "ebook".
+	// An empty TaxCode resolves to ProductClassStandard.
+	TaxCode string
+}
+
+// TaxCalculationInput is the input to Ctrl.CalculateTax.
+type TaxCalculationInput struct {
+	// CustomerDetails identifies the buyer and their declared tax IDs.
+	CustomerDetails CustomerDetails
+	// OriginAddress is the address the sale is shipped or supplied from.
+	OriginAddress Address
+	// Currency is the three-letter ISO currency code of all amounts.
+	Currency string
+	// AmountTotal is the total transaction amount in the smallest currency unit, informational only.
+	AmountTotal int64
+	// ShippingCost is the shipping line item, if the transaction has one.
+	ShippingCost *LineItem
+	// LineItems are the taxable lines of the transaction.
+	LineItems []LineItem
+}
+
+// TaxRateDetails describes the jurisdiction and rate behind a TaxBreakdown entry.
+type TaxRateDetails struct {
+	// DisplayName is a human-readable label for the rate, e.g. "VAT".
+	DisplayName string
+	// PercentageDecimal is the rate as a decimal fraction, e.g. 0.19 for 19%.
+	PercentageDecimal float32
+	// TaxType is the underlying tax type, e.g. "vat", "gst" or "qst".
+	TaxType string
+	// Jurisdiction is the country or state code the rate applies in.
+	Jurisdiction string
+}
+
+// TaxBreakdown is one jurisdiction's contribution to a LineItemTax.
+type TaxBreakdown struct {
+	// Amount is the portion of AmountTax attributable to this jurisdiction.
+	Amount int64
+	// TaxRateDetails describes the rate that produced Amount.
+	TaxRateDetails TaxRateDetails
+}
+
+// LineItemTax is the computed tax for a single LineItem or the ShippingCost line.
+type LineItemTax struct {
+	// Reference echoes the Reference of the LineItem this tax was computed for.
+	Reference string
+	// AmountTax is the total tax owed on this line, across all jurisdictions.
+	AmountTax int64
+	// TaxBreakdown itemizes AmountTax by jurisdiction.
+	TaxBreakdown []TaxBreakdown
+}
+
+// VerifiedTaxID is a customer TaxID alongside whether it was verified as valid.
+type VerifiedTaxID struct {
+	TaxID
+	// Verified is true if the ID was confirmed to belong to a registered business.
+	Verified bool
+}
+
+// TaxCalculationCustomerDetails mirrors CustomerDetails on the resulting TaxCalculation,
+// with each declared TaxID resolved to a verification state.
+type TaxCalculationCustomerDetails struct {
+	// Address is the customer's address, as supplied on the input.
+	Address Address
+	// TaxIDs are the customer's declared tax IDs, each marked verified or unverified.
+	TaxIDs []VerifiedTaxID
+}
+
+// TaxCalculation is the result of Ctrl.CalculateTax, modeled on Stripe Tax's tax_calculation
+// object: it breaks tax down per line item and jurisdiction rather than returning a single rate.
+type TaxCalculation struct {
+	// ID is a stable identifier for this calculation, for passing to CreateTransaction later.
+	ID string
+	// Currency is the three-letter ISO currency code of all amounts, echoed from the input.
+	Currency string
+	// AmountTotal is the total transaction amount, echoed from the input.
+	AmountTotal int64
+	// TaxAmountExclusive is the sum of AmountTax across exclusive line items.
+	TaxAmountExclusive int64
+	// TaxAmountInclusive is the sum of AmountTax across inclusive line items.
+	TaxAmountInclusive int64
+	// CustomerDetails is the resolved customer address and tax ID verification state.
+	CustomerDetails TaxCalculationCustomerDetails
+	// LineItems holds the computed tax for each input LineItem, in the same order.
+	LineItems []LineItemTax
+	// ShippingCost is the computed tax for the input ShippingCost, if one was supplied.
+	ShippingCost *LineItemTax
+}
+
+// CalculateTax computes tax for a full transaction, rather than a single (country, state)
+// tuple: it resolves the buyer's jurisdiction once via resolveSalesTaxComponents and applies
+// that rate per line item, honoring each line's TaxBehavior and rounding independently before
+// summing, the same way Stripe Tax rounds per line rather than on the transaction total.
+func (t *Ctrl) CalculateTax(ctx context.Context, input TaxCalculationInput) (*TaxCalculation, error) {
+	countryCode := strings.ToUpper(input.CustomerDetails.Address.Country)
+	stateCode := input.CustomerDetails.Address.State
+
+	var taxNumber *string
+	if len(input.CustomerDetails.TaxIDs) > 0 {
+		taxNumber = &input.CustomerDetails.TaxIDs[0].Value
+	}
+
+	countryTax, stateTax, _, taxExchange, isExempt, verification, err := t.resolveSalesTaxComponents(ctx, countryCode, stateCode, taxNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sales tax components: %w", err)
+	}
+
+	calc := &TaxCalculation{
+		ID:          newCalculationID(),
+		Currency:    input.Currency,
+		AmountTotal: input.AmountTotal,
+		CustomerDetails: TaxCalculationCustomerDetails{
+			Address: input.CustomerDetails.Address,
+			TaxIDs:  verifyTaxIDs(input.CustomerDetails.TaxIDs, taxNumber, verification, taxExchange),
+		},
+	}
+
+	for _, item := range input.LineItems {
+		itemTax := calculateLineItemTax(item, countryCode, stateCode, countryTax, stateTax, isExempt)
+		calc.LineItems = append(calc.LineItems, itemTax)
+		addLineTax(calc, item.TaxBehavior, itemTax.AmountTax)
+	}
+
+	if input.ShippingCost != nil {
+		shippingTax := calculateLineItemTax(*input.ShippingCost, countryCode, stateCode, countryTax, stateTax, isExempt)
+		calc.ShippingCost = &shippingTax
+		addLineTax(calc, input.ShippingCost.TaxBehavior, shippingTax.AmountTax)
+	}
+
+	return calc, nil
+}
+
+func addLineTax(calc *TaxCalculation, behavior TaxBehavior, amountTax int64) {
+	if behavior == TaxBehaviorInclusive {
+		calc.TaxAmountInclusive += amountTax
+	} else {
+		calc.TaxAmountExclusive += amountTax
+	}
+}
+
+// calculateLineItemTax computes item's tax, split by jurisdiction. The split always starts
+// from a single tax amount computed against the combined country+state rate, then allocates
+// that amount across jurisdictions by each rate's share of the combined rate: for an inclusive
+// line item, backing out net per jurisdiction independently (amount/(1+rate) against only that
+// jurisdiction's own rate) would extract too much tax whenever both rates are nonzero, since the
+// embedded tax is a single amount computed against their sum, not two independently embedded
+// amounts.
+func calculateLineItemTax(item LineItem, countryCode string, stateCode *string, countryTax, stateTax *taxRate, isExempt bool) LineItemTax {
+	lineTax := LineItemTax{Reference: item.Reference}
+	if isExempt {
+		return lineTax
+	}
+
+	amount := item.Amount * item.Quantity
+
+	// The category a productClass resolves to carries its own independent PreviousRecordings
+	// history, same as GetSalesTaxAt's resolveRateAt(resolveProductClassRate(...), at) does for
+	// the single-rate API; CalculateTax has no "at" parameter of its own, so it resolves against
+	// currentTime() like the legacy GetSalesTax entry point does.
+	countryTax = resolveRateAt(resolveProductClassRate(countryTax, productClassOf(item)), currentTime())
+
+	totalRate := countryTax.TaxRate + stateTax.TaxRate
+	if totalRate <= 0 {
+		return lineTax
+	}
+
+	totalTax := roundTaxAmount(amount, totalRate, item.TaxBehavior)
+
+	switch {
+	case countryTax.TaxRate > 0 && stateTax.TaxRate > 0:
+		countryShare := splitTaxShare(totalTax, countryTax.TaxRate, totalRate)
+		lineTax.TaxBreakdown = append(lineTax.TaxBreakdown, newTaxBreakdown(countryShare, countryTax, countryCode))
+		lineTax.TaxBreakdown = append(lineTax.TaxBreakdown, newTaxBreakdown(totalTax-countryShare, stateTax, stateJurisdiction(countryCode, stateCode)))
+	case countryTax.TaxRate > 0:
+		lineTax.TaxBreakdown = append(lineTax.TaxBreakdown, newTaxBreakdown(totalTax, countryTax, countryCode))
+	default:
+		lineTax.TaxBreakdown = append(lineTax.TaxBreakdown, newTaxBreakdown(totalTax, stateTax, stateJurisdiction(countryCode, stateCode)))
+	}
+
+	for _, breakdown := range lineTax.TaxBreakdown {
+		lineTax.AmountTax += breakdown.Amount
+	}
+
+	return lineTax
+}
+
+// stateJurisdiction returns stateCode if set, falling back to countryCode for display when a
+// state-level rate applies without a specific state being named (e.g. a country-wide GST add-on).
+func stateJurisdiction(countryCode string, stateCode *string) string {
+	if stateCode != nil {
+		return *stateCode
+	}
+
+	return countryCode
+}
+
+// splitTaxShare allocates totalTax (already computed against the combined country+state rate)
+// to the jurisdiction charging rate, proportionally to its share of totalRate.
+func splitTaxShare(totalTax int64, rate, totalRate float32) int64 {
+	return int64(float32(totalTax)*(rate/totalRate) + 0.5)
+}
+
+func newTaxBreakdown(amount int64, rate *taxRate, jurisdiction string) TaxBreakdown {
+	return TaxBreakdown{
+		Amount: amount,
+		TaxRateDetails: TaxRateDetails{
+			DisplayName:       strings.ToUpper(rate.TaxType),
+			PercentageDecimal: rate.TaxRate,
+			TaxType:           rate.TaxType,
+			Jurisdiction:      jurisdiction,
+		},
+	}
+}
+
+// roundTaxAmount computes the tax owed on amount at the combined rate, rounded to the nearest
+// unit of the smallest currency denomination. For inclusive line items, amount already includes
+// the tax; callers with both a country and state rate must pass their sum here and split the
+// result with splitTaxShare, rather than rounding each jurisdiction's share independently.
+func roundTaxAmount(amount int64, rate float32, behavior TaxBehavior) int64 {
+	if behavior == TaxBehaviorInclusive {
+		net := float32(amount) / (1 + rate)
+		return amount - int64(net+0.5)
+	}
+
+	return int64(float32(amount)*rate + 0.5)
+}
+
+// productClassOf resolves a LineItem's ProductClass from its TaxCode, if any was set.
+func productClassOf(item LineItem) *ProductClass {
+	if item.TaxCode == "" {
+		return nil
+	}
+
+	return Ptr(ProductClass(item.TaxCode))
+}
+
+// verifyTaxIDs resolves each declared TaxID to a verification state. The ID that was actually
+// checked (verifiedValue) gets the real VerificationResult when one is available; any other
+// declared ID falls back to the overall tax exchange status, matching the pre-verifier behavior.
+func verifyTaxIDs(taxIDs []TaxID, verifiedValue *string, verification *VerificationResult, taxExchange TaxExchange) []VerifiedTaxID {
+	if len(taxIDs) == 0 {
+		return nil
+	}
+
+	verified := make([]VerifiedTaxID, 0, len(taxIDs))
+	for _, id := range taxIDs {
+		isVerifiedValue := verifiedValue != nil && id.Value == *verifiedValue
+
+		valid := taxExchange == TaxExchangeBusiness
+		if isVerifiedValue && verification != nil {
+			valid = verification.Valid
+		}
+
+		verified = append(verified, VerifiedTaxID{TaxID: id, Verified: valid})
+	}
+
+	return verified
+}
+
+// newCalculationID generates a stable, opaque identifier for a TaxCalculation, in the style
+// of Stripe object IDs, so callers can persist it and later reference it from CreateTransaction.
+func newCalculationID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "taxcalc_00000000000000000000000000000000"
+	}
+
+	return "taxcalc_" + hex.EncodeToString(buf)
+}
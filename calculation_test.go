@@ -0,0 +1,175 @@
+package salestax
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_CalculateTax(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		ctrl                 *Ctrl
+		input                TaxCalculationInput
+		expectedTaxExclusive int64
+		expectedTaxInclusive int64
+		expectedLineTax      []int64
+	}{
+		{
+			name: "national exclusive line items",
+			ctrl: &Ctrl{OriginCountryCode: Ptr("DE")},
+			input: TaxCalculationInput{
+				CustomerDetails: CustomerDetails{Address: Address{Country: "DE"}},
+				Currency:        "EUR",
+				LineItems: []LineItem{
+					{Reference: "line_1", Amount: 10000, Quantity: 1, TaxBehavior: TaxBehaviorExclusive},
+					{Reference: "line_2", Amount: 5000, Quantity: 2, TaxBehavior: TaxBehaviorExclusive},
+				},
+			},
+			expectedTaxExclusive: 1900 + 1900,
+			expectedLineTax:      []int64{1900, 1900},
+		},
+		{
+			name: "regional inclusive line item via OSS",
+			ctrl: &Ctrl{OriginCountryCode: Ptr("DE"), ThresholdPolicy: &ThresholdPolicy{OSSRegistered: true}},
+			input: TaxCalculationInput{
+				CustomerDetails: CustomerDetails{Address: Address{Country: "FR"}},
+				Currency:        "EUR",
+				LineItems: []LineItem{
+					{Reference: "line_1", Amount: 12000, Quantity: 1, TaxBehavior: TaxBehaviorInclusive},
+				},
+			},
+			expectedTaxInclusive: 2000,
+			expectedLineTax:      []int64{2000},
+		},
+		{
+			name: "regional reverse-charge B2B is tax-exempt",
+			ctrl: &Ctrl{OriginCountryCode: Ptr("DE"), ThresholdPolicy: &ThresholdPolicy{OSSRegistered: true}},
+			input: TaxCalculationInput{
+				CustomerDetails: CustomerDetails{
+					Address: Address{Country: "FR"},
+					TaxIDs:  []TaxID{{Type: "eu_vat", Value: "FR000000000"}},
+				},
+				Currency: "EUR",
+				LineItems: []LineItem{
+					{Reference: "line_1", Amount: 10000, Quantity: 1, TaxBehavior: TaxBehaviorExclusive},
+				},
+			},
+			expectedLineTax: []int64{0},
+		},
+		{
+			name: "reduced product class line item",
+			ctrl: &Ctrl{OriginCountryCode: Ptr("DE")},
+			input: TaxCalculationInput{
+				CustomerDetails: CustomerDetails{Address: Address{Country: "DE"}},
+				Currency:        "EUR",
+				LineItems: []LineItem{
+					{Reference: "book", Amount: 10000, Quantity: 1, TaxBehavior: TaxBehaviorExclusive, TaxCode: "reduced"},
+				},
+			},
+			expectedTaxExclusive: 700,
+			expectedLineTax:      []int64{700},
+		},
+	}
+
+	for _, tc := range testCases {
+		calc, err := tc.ctrl.CalculateTax(context.Background(), tc.input)
+		if err != nil {
+			t.Errorf("%s: got error: %s", tc.name, err.Error())
+			continue
+		}
+
+		if calc.TaxAmountExclusive != tc.expectedTaxExclusive {
+			t.Errorf("%s: expected exclusive tax %d; got %d", tc.name, tc.expectedTaxExclusive, calc.TaxAmountExclusive)
+		}
+		if calc.TaxAmountInclusive != tc.expectedTaxInclusive {
+			t.Errorf("%s: expected inclusive tax %d; got %d", tc.name, tc.expectedTaxInclusive, calc.TaxAmountInclusive)
+		}
+		if len(calc.LineItems) != len(tc.expectedLineTax) {
+			t.Errorf("%s: expected %d line items; got %d", tc.name, len(tc.expectedLineTax), len(calc.LineItems))
+			continue
+		}
+		for i, lineTax := range calc.LineItems {
+			if lineTax.AmountTax != tc.expectedLineTax[i] {
+				t.Errorf("%s: line %d: expected tax %d; got %d", tc.name, i, tc.expectedLineTax[i], lineTax.AmountTax)
+			}
+		}
+		if calc.ID == "" {
+			t.Errorf("%s: expected a non-empty calculation ID", tc.name)
+		}
+	}
+}
+
+func Test_verifyTaxIDs(t *testing.T) {
+	taxIDs := []TaxID{{Type: "eu_vat", Value: "FR000000000"}}
+
+	verified := verifyTaxIDs(taxIDs, nil, nil, TaxExchangeBusiness)
+	if len(verified) != 1 || !verified[0].Verified {
+		t.Errorf("expected a verified tax ID for a business exchange; got %+v", verified)
+	}
+
+	unverified := verifyTaxIDs(taxIDs, nil, nil, TaxExchangeConsumer)
+	if len(unverified) != 1 || unverified[0].Verified {
+		t.Errorf("expected an unverified tax ID for a consumer exchange; got %+v", unverified)
+	}
+}
+
+// Test_CalculateTax_productClassHistory pins down that a line item's category rate is resolved
+// against its own PreviousRecordings history, not just its current top-level rate, the same as
+// GetSalesTaxAt already does for a single-rate lookup.
+func Test_CalculateTax_productClassHistory(t *testing.T) {
+	currentTime = func() time.Time {
+		return time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	}
+	defer func() { currentTime = time.Now }()
+
+	ctrl := &Ctrl{OriginCountryCode: Ptr("DE")}
+
+	calc, err := ctrl.CalculateTax(context.Background(), TaxCalculationInput{
+		CustomerDetails: CustomerDetails{Address: Address{Country: "DE"}},
+		Currency:        "EUR",
+		LineItems: []LineItem{
+			{Reference: "book", Amount: 10000, Quantity: 1, TaxBehavior: TaxBehaviorExclusive, TaxCode: "reduced"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	if len(calc.LineItems) != 1 {
+		t.Fatalf("expected 1 line item; got %d", len(calc.LineItems))
+	}
+	if calc.LineItems[0].AmountTax != 500 {
+		t.Errorf("expected the reduced category's COVID-cut 5%% rate to apply; got tax %d", calc.LineItems[0].AmountTax)
+	}
+}
+
+// Test_calculateLineItemTax_inclusiveCombinedCountryAndState pins down that an inclusive line
+// item with both a country and a state rate backs out the embedded tax once against their
+// combined rate, then splits it, rather than backing it out independently per jurisdiction
+// (which double-deducts and never sums back to the embedded total).
+func Test_calculateLineItemTax_inclusiveCombinedCountryAndState(t *testing.T) {
+	countryTax := &taxRate{TaxType: "vat", TaxRate: 0.05}
+	stateTax := &taxRate{TaxType: "vat", TaxRate: 0.04}
+
+	item := LineItem{Reference: "line_1", Amount: 10900, Quantity: 1, TaxBehavior: TaxBehaviorInclusive}
+
+	lineTax := calculateLineItemTax(item, "US", Ptr("NY"), countryTax, stateTax, false)
+
+	expectedTotal := roundTaxAmount(10900, 0.09, TaxBehaviorInclusive)
+	if lineTax.AmountTax != expectedTotal {
+		t.Errorf("expected the combined inclusive tax backed out against the 9%% combined rate (%d); got %d", expectedTotal, lineTax.AmountTax)
+	}
+
+	if len(lineTax.TaxBreakdown) != 2 {
+		t.Fatalf("expected one breakdown entry per jurisdiction; got %d", len(lineTax.TaxBreakdown))
+	}
+
+	var sum int64
+	for _, breakdown := range lineTax.TaxBreakdown {
+		sum += breakdown.Amount
+	}
+	if sum != expectedTotal {
+		t.Errorf("expected the per-jurisdiction breakdown amounts to sum to %d; got %d", expectedTotal, sum)
+	}
+}
@@ -1,11 +1,13 @@
 package salestax
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +25,24 @@ const (
 	TaxAreaRegional  TaxArea = "regional"
 )
 
+// ProductClass identifies a canonical product category for rate lookups, such as the
+// reduced, super-reduced or zero VAT rates most EU jurisdictions apply to books, food
+// or hospitality. ProductClassStandard (or an empty string) selects the default rate.
+type ProductClass string
+
+const (
+	// ProductClassStandard is the default rate a country or state charges.
+	ProductClassStandard ProductClass = "standard"
+	// ProductClassReduced is the general reduced VAT rate, where one exists.
+	ProductClassReduced ProductClass = "reduced"
+	// ProductClassSuperReduced is a further-reduced VAT rate offered by some EU states.
+	ProductClassSuperReduced ProductClass = "super_reduced"
+	// ProductClassZero is a 0% rate applied to specific goods in some jurisdictions.
+	ProductClassZero ProductClass = "zero"
+	// ProductClassExempt marks goods that are outside the scope of tax entirely.
+	ProductClassExempt ProductClass = "exempt"
+)
+
 // SalesTax is the sales tax object.
 type SalesTax struct {
 	// Type is the type of the sales tax.
@@ -35,6 +55,15 @@ type SalesTax struct {
 	Exchange TaxExchange
 	// Charge contains information about the charge types of the sales tax.
 	Charge TaxCharge
+	// TaxNumberVerification holds the outcome of verifying the supplied tax number against
+	// Ctrl.VATVerifier. It is nil unless a tax number was supplied and a verifier is configured.
+	TaxNumberVerification *VerificationResult
+	// CategoryCode is the UNCL5305 tax category code for this sale, for e-invoicing formats
+	// such as Peppol BIS, FatturaPA or the Romanian/Hungarian e-invoicing schemas.
+	CategoryCode string
+	// CategoryReason is the EN 16931 exemption reason code backing CategoryCode, when one
+	// applies (e.g. "VATEX-EU-IC" for an intra-community supply). Empty when not applicable.
+	CategoryReason string
 }
 
 // TaxCharge contains information about the charge types of the sales tax.
@@ -49,101 +78,167 @@ type TaxCharge struct {
 type Ctrl struct {
 	// OriginCountryCode is the country code of the tax registration and liability.
 	OriginCountryCode *string
-	// RegionalTaxEnabled specifies whether regional taxation is enabled, such as in the EU region.
-	// If this value is set to true (VAT OSS threshold is not exceeded), the rate of the origin country will be used.
-	RegionalTaxEnabled bool
+	// ThresholdPolicy tracks the seller's EU VAT OSS distance-selling position, and decides
+	// whether regional sales charge the origin country's VAT or the destination country's VAT
+	// via OSS. A nil ThresholdPolicy behaves as a fresh policy: not OSS-registered and no sales
+	// recorded yet, so regional sales charge the origin country's VAT until it is fed via
+	// RecordSale or OSSRegistered is set.
+	ThresholdPolicy *ThresholdPolicy
+	// VATVerifier, when set, is used to verify a buyer-supplied tax number before it is
+	// trusted to grant business (reverse-charge) status. When nil, any non-nil tax number
+	// is accepted as-is, matching the legacy behavior.
+	VATVerifier VATVerifier
+	// VATVerificationCacheTTL controls how long a VATVerifier result is cached for.
+	// Defaults to defaultVerificationCacheTTL when zero.
+	VATVerificationCacheTTL time.Duration
+
+	regionCountries   map[string][]string
+	taxRates          map[string]taxRate
+	verificationCache sync.Map
+}
 
-	regionCountries map[string][]string
-	taxRates        map[string]taxRate
+// GetSalesTax returns the sales tax for the desired country, as it stands now.
+// The parameters stateCode, taxNumber and productClass are optional; a nil or empty
+// productClass resolves to ProductClassStandard.
+func (t *Ctrl) GetSalesTax(ctx context.Context, countryCode string, stateCode *string, taxNumber *string, productClass *ProductClass) (*SalesTax, error) {
+	return t.GetSalesTaxAt(ctx, countryCode, stateCode, taxNumber, productClass, currentTime())
 }
 
-// GetSalesTax returns the sales tax for the desired country.
-// The parameters stateCode and taxNumber are optional.
-func (t *Ctrl) GetSalesTax(countryCode string, stateCode *string, taxNumber *string) (*SalesTax, error) {
+// GetSalesTaxAt returns the sales tax for the desired country as it stood at the given time,
+// for pricing historical invoices such as credit notes or corrections. It consults the same
+// PreviousRecordings data as GetSalesTax, but against at rather than the current time.
+// The parameters stateCode, taxNumber and productClass are optional; a nil or empty
+// productClass resolves to ProductClassStandard.
+func (t *Ctrl) GetSalesTaxAt(ctx context.Context, countryCode string, stateCode *string, taxNumber *string, productClass *ProductClass, at time.Time) (*SalesTax, error) {
+	countryTax, stateTax, targetArea, taxExchange, isExempt, verification, err := t.resolveSalesTaxComponentsAt(ctx, countryCode, stateCode, taxNumber, at)
+	if err != nil {
+		return nil, err
+	}
+
+	countryTax = resolveRateAt(resolveProductClassRate(countryTax, productClass), at)
+
+	totalRate := countryTax.TaxRate + stateTax.TaxRate
+	taxType := countryTax.TaxType
+	if stateTax.TaxRate > 0 {
+		if countryTax.TaxRate > 0 {
+			taxType = fmt.Sprintf("%s+%s", taxType, stateTax.TaxType)
+		} else {
+			taxType = stateTax.TaxType
+		}
+	}
+
+	taxCharge := TaxCharge{}
+	if taxType != "none" {
+		taxCharge.Direct = !isExempt
+		taxCharge.Reverse = isExempt && totalRate > 0
+	}
+
+	taxRate := totalRate
+	if isExempt {
+		totalRate = 0
+	}
+
+	known, err := t.isKnownCountry(countryCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine whether %s is a known country: %w", countryCode, err)
+	}
+
+	categoryCode, categoryReason := computeTaxCategory(targetArea, taxCharge, productClass, known)
+
+	return &SalesTax{
+		Type:                  taxType,
+		Rate:                  taxRate,
+		Area:                  targetArea,
+		Exchange:              taxExchange,
+		Charge:                taxCharge,
+		TaxNumberVerification: verification,
+		CategoryCode:          categoryCode,
+		CategoryReason:        categoryReason,
+	}, nil
+}
+
+// resolveSalesTaxComponents resolves the country- and state-level tax rates, the target
+// area and the buyer's tax exchange status for a (country, state, taxNumber) tuple, as they
+// stand now. It is shared by GetSalesTax and the line-item based CalculateTax so both stay
+// in sync.
+func (t *Ctrl) resolveSalesTaxComponents(ctx context.Context, countryCode string, stateCode *string, taxNumber *string) (countryTax *taxRate, stateTax *taxRate, targetArea TaxArea, taxExchange TaxExchange, isExempt bool, verification *VerificationResult, err error) {
+	return t.resolveSalesTaxComponentsAt(ctx, countryCode, stateCode, taxNumber, currentTime())
+}
+
+// resolveSalesTaxComponentsAt is resolveSalesTaxComponents against an explicit point in time,
+// so GetSalesTaxAt can price historical invoices.
+func (t *Ctrl) resolveSalesTaxComponentsAt(ctx context.Context, countryCode string, stateCode *string, taxNumber *string, at time.Time) (countryTax *taxRate, stateTax *taxRate, targetArea TaxArea, taxExchange TaxExchange, isExempt bool, verification *VerificationResult, err error) {
 	countryCode = strings.ToUpper(countryCode)
 	if stateCode != nil {
 		stateCode = Ptr(strings.ToUpper(*stateCode))
 	}
-	targetArea, err := t.getTargetArea(countryCode)
+
+	area, err := t.getTargetArea(countryCode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get target area: %w", err)
+		return nil, nil, "", "", false, nil, fmt.Errorf("failed to get target area: %w", err)
 	}
 
-	var countryTax, stateTax *taxRate
+	targetArea = *area
+
+	ossActive := true
+	if targetArea == TaxAreaRegional {
+		ossActive, err = t.thresholdPolicy().ossActive()
+		if err != nil {
+			return nil, nil, "", "", false, nil, fmt.Errorf("failed to determine OSS threshold status: %w", err)
+		}
+	}
 
-	if *targetArea == TaxAreaRegional && !t.RegionalTaxEnabled && t.OriginCountryCode != nil {
-		countryTax, err = t.getSalesTaxRate(*t.OriginCountryCode)
+	if targetArea == TaxAreaRegional && !ossActive && t.OriginCountryCode != nil {
+		countryTax, err = t.getSalesTaxRateAt(*t.OriginCountryCode, at)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get country tax rate for %s: %w", *t.OriginCountryCode, err)
+			return nil, nil, "", "", false, nil, fmt.Errorf("failed to get country tax rate for %s: %w", *t.OriginCountryCode, err)
 		}
 
 		stateTax = Ptr(defaultTaxRate)
 	} else {
-		countryTax, err = t.getSalesTaxRate(countryCode)
+		countryTax, err = t.getSalesTaxRateAt(countryCode, at)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get country tax rate for %s: %w", countryCode, err)
+			return nil, nil, "", "", false, nil, fmt.Errorf("failed to get country tax rate for %s: %w", countryCode, err)
 		}
 
+		stateTax = Ptr(defaultTaxRate)
 		if countryTax.States != nil && stateCode != nil {
-			tax, ok := countryTax.States[*stateCode]
-			if !ok {
-				stateTax = Ptr(defaultTaxRate)
+			if tax, ok := countryTax.States[*stateCode]; ok {
+				stateTax = resolveRateAt(&tax, at)
 			}
-
-			stateTax = &tax
-		} else {
-			stateTax = Ptr(defaultTaxRate)
 		}
 	}
 
-	taxExchange := TaxExchangeConsumer
-	isExempt := false
-	totalRate := countryTax.TaxRate + stateTax.TaxRate
+	taxExchange = TaxExchangeConsumer
 
 	if countryTax.TaxRate > 0 || stateTax.TaxRate > 0 {
-		exchangeStatus, exemptStatus, err := t.getTaxExchangeStatus(countryCode, stateCode, taxNumber)
+		exchangeStatus, exemptStatus, verificationResult, err := t.getTaxExchangeStatusAt(ctx, countryCode, stateCode, taxNumber, at)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get tax exchange status: %w", err)
+			return nil, nil, "", "", false, nil, fmt.Errorf("failed to get tax exchange status: %w", err)
 		}
 
 		taxExchange = *exchangeStatus
 		isExempt = exemptStatus
+		verification = verificationResult
 	}
 
-	taxType := countryTax.TaxType
-	if stateTax.TaxRate > 0 {
-		if countryTax.TaxRate > 0 {
-			taxType = fmt.Sprintf("%s+%s", taxType, stateTax.TaxType)
-		} else {
-			taxType = stateTax.TaxType
-		}
-	}
-
-	taxCharge := TaxCharge{}
-	if taxType != "none" {
-		taxCharge.Direct = !isExempt
-		taxCharge.Reverse = isExempt && totalRate > 0
-	}
-
-	taxRate := totalRate
-	if isExempt {
-		totalRate = 0
-	}
-
-	return &SalesTax{
-		Type:     taxType,
-		Rate:     taxRate,
-		Area:     *targetArea,
-		Exchange: taxExchange,
-		Charge:   taxCharge,
-	}, nil
+	return countryTax, stateTax, targetArea, taxExchange, isExempt, verification, nil
 }
 
 type taxRate struct {
-	TaxType            string             `json:"type"`
-	TaxRate            float32            `json:"rate"`
+	TaxType string  `json:"type"`
+	TaxRate float32 `json:"rate"`
+	// EffectiveFrom and EffectiveTo bound the window in which this rate applies, when it is a
+	// historical entry inside another rate's PreviousRecordings. A nil bound is unbounded on
+	// that side. They are ignored on a top-level (current) rate.
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+	// PreviousRecordings holds historical overrides of this rate, such as a temporary COVID-era
+	// cut, keyed by an arbitrary descriptive label. getSalesTaxRateAt selects the entry whose
+	// [EffectiveFrom, EffectiveTo) window contains the queried time, if any.
 	PreviousRecordings map[string]taxRate `json:"before,omitempty"`
 	States             map[string]taxRate `json:"states,omitempty"`
+	Categories         map[string]taxRate `json:"categories,omitempty"`
 }
 
 var (
@@ -151,39 +246,66 @@ var (
 	regionCountriesData []byte
 	//go:embed res/sales_tax_rates.json
 	salesTaxRatesData []byte
-	currentTime       = time.Now
-	defaultTaxRate    = taxRate{"none", 0, nil, nil}
+	// currentTime returns the time used by getSalesTaxRate and the legacy GetSalesTax entry
+	// point to resolve historical rates.
+	//
+	// Deprecated: pricing against the real wall clock via a package-level var makes it
+	// impossible to price historical invoices without racing on global state. Callers that
+	// need a specific point in time should use GetSalesTaxAt instead.
+	currentTime    = time.Now
+	defaultTaxRate = taxRate{TaxType: "none"}
 )
 
-func (t *Ctrl) getTaxExchangeStatus(countryCode string, stateCode *string, taxNumber *string) (status *TaxExchange, exempt bool, err error) {
+func (t *Ctrl) getTaxExchangeStatus(ctx context.Context, countryCode string, stateCode *string, taxNumber *string) (status *TaxExchange, exempt bool, verification *VerificationResult, err error) {
+	return t.getTaxExchangeStatusAt(ctx, countryCode, stateCode, taxNumber, currentTime())
+}
+
+func (t *Ctrl) getTaxExchangeStatusAt(ctx context.Context, countryCode string, stateCode *string, taxNumber *string, at time.Time) (status *TaxExchange, exempt bool, verification *VerificationResult, err error) {
 	targetArea, err := t.getTargetArea(countryCode)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to get target area: %w", err)
+		return nil, false, nil, fmt.Errorf("failed to get target area: %w", err)
 	}
 
-	hasTotalSalesTax, err := t.hasTotalSalesTax(countryCode, stateCode)
+	hasTotalSalesTax, err := t.hasTotalSalesTaxAt(countryCode, stateCode, at)
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to determine whether a sales tax is applicable: %w", err)
+		return nil, false, nil, fmt.Errorf("failed to determine whether a sales tax is applicable: %w", err)
 	}
 
 	if hasTotalSalesTax {
 		if taxNumber != nil {
-			return Ptr(TaxExchangeBusiness), *targetArea != TaxAreaNational, nil
+			if t.VATVerifier == nil {
+				return Ptr(TaxExchangeBusiness), *targetArea != TaxAreaNational, nil, nil
+			}
+
+			result, err := t.verifyTaxNumber(ctx, countryCode, *taxNumber)
+			if err != nil {
+				return nil, false, nil, fmt.Errorf("failed to verify tax number: %w", err)
+			}
+
+			if !result.Valid {
+				return Ptr(TaxExchangeConsumer), false, result, nil
+			}
+
+			return Ptr(TaxExchangeBusiness), *targetArea != TaxAreaNational, result, nil
 		}
 
-		return Ptr(TaxExchangeConsumer), false, nil
+		return Ptr(TaxExchangeConsumer), false, nil, nil
 	}
 
-	return Ptr(TaxExchangeConsumer), true, nil
+	return Ptr(TaxExchangeConsumer), true, nil, nil
 }
 
 func (t *Ctrl) hasTotalSalesTax(countryCode string, stateCode *string) (bool, error) {
+	return t.hasTotalSalesTaxAt(countryCode, stateCode, currentTime())
+}
+
+func (t *Ctrl) hasTotalSalesTaxAt(countryCode string, stateCode *string, at time.Time) (bool, error) {
 	countryCode = strings.ToUpper(countryCode)
 	if stateCode != nil {
 		stateCode = Ptr(strings.ToUpper(*stateCode))
 	}
 
-	rate, err := t.getSalesTaxRate(countryCode)
+	rate, err := t.getSalesTaxRateAt(countryCode, at)
 	if err != nil {
 		return false, fmt.Errorf("failed to get country tax rate for %s: %w", countryCode, err)
 	}
@@ -191,16 +313,67 @@ func (t *Ctrl) hasTotalSalesTax(countryCode string, stateCode *string) (bool, er
 	totalTax := rate.TaxRate
 
 	if stateCode != nil {
-		rate, ok := rate.States[*stateCode]
-		if ok {
-			totalTax += rate.TaxRate
+		if stateRate, ok := rate.States[*stateCode]; ok {
+			totalTax += resolveRateAt(&stateRate, at).TaxRate
 		}
 	}
 
 	return totalTax > 0, nil
 }
 
+// resolveProductClassRate resolves rate to the taxRate registered for productClass, falling
+// back from category to the country default, and finally to rate itself when no category
+// (or no matching category) is given. Rates for ProductClassStandard are never overridden.
+func resolveProductClassRate(rate *taxRate, productClass *ProductClass) *taxRate {
+	if productClass == nil || *productClass == "" || *productClass == ProductClassStandard {
+		return rate
+	}
+
+	if rate.Categories != nil {
+		if categoryRate, ok := rate.Categories[string(*productClass)]; ok {
+			return &categoryRate
+		}
+	}
+
+	return rate
+}
+
+// ListProductClasses returns the product classes with a dedicated rate for countryCode,
+// beyond ProductClassStandard, for use in UI dropdowns. The result is sorted and does not
+// include ProductClassStandard, which is always available.
+func (t *Ctrl) ListProductClasses(countryCode string) ([]string, error) {
+	countryCode = strings.ToUpper(countryCode)
+
+	rate, err := t.getSalesTaxRate(countryCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get country tax rate for %s: %w", countryCode, err)
+	}
+
+	classes := make([]string, 0, len(rate.Categories))
+	for class := range rate.Categories {
+		classes = append(classes, class)
+	}
+
+	slices.Sort(classes)
+
+	return classes, nil
+}
+
+// getSalesTaxRate returns the current rate for countryCode, using currentTime to resolve any
+// historical override in effect right now.
+//
+// Deprecated: use getSalesTaxRateAt with an explicit time to avoid depending on the package-
+// level currentTime var.
 func (t *Ctrl) getSalesTaxRate(countryCode string) (*taxRate, error) {
+	return t.getSalesTaxRateAt(countryCode, currentTime())
+}
+
+// getSalesTaxRateAt returns the rate for countryCode in effect at the given time, with its
+// type and rate overridden from rate.PreviousRecordings if a historical entry's
+// [EffectiveFrom, EffectiveTo) window contains at. Its States and Categories are always those
+// of the current (top-level) rate; resolveRateAt must be applied again after selecting a
+// state or category sub-rate, since each carries its own independent history.
+func (t *Ctrl) getSalesTaxRateAt(countryCode string, at time.Time) (*taxRate, error) {
 	rates, err := t.getSalesTaxRates()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sales tax rates: %w", err)
@@ -211,34 +384,46 @@ func (t *Ctrl) getSalesTaxRate(countryCode string) (*taxRate, error) {
 		return Ptr(defaultTaxRate), nil
 	}
 
-	if rate.PreviousRecordings != nil {
-		var activeDateKey *string
-		var activeDate *time.Time
+	return resolveRateAt(&rate, at), nil
+}
 
-		currentDate := currentTime()
-		dateLayout := "2006-01-02T15:04:05.000Z"
+// resolveRateAt returns rate with its TaxType and TaxRate overridden from the entry in
+// rate.PreviousRecordings whose [EffectiveFrom, EffectiveTo) window contains at, if any. Its
+// States, Categories and PreviousRecordings are left untouched, so a caller that drills into a
+// state or category sub-rate can apply resolveRateAt again to resolve that sub-rate's own,
+// independent history.
+func resolveRateAt(rate *taxRate, at time.Time) *taxRate {
+	override := activeRecording(rate.PreviousRecordings, at)
+	if override == nil {
+		return rate
+	}
 
-		for dateStr := range rate.PreviousRecordings {
-			dateStr := dateStr
-			date, err := time.Parse(dateLayout, dateStr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse date %s: %w", dateStr, err)
-			}
+	resolved := *rate
+	resolved.TaxType = override.TaxType
+	resolved.TaxRate = override.TaxRate
 
-			if currentDate.Before(date) {
-				if activeDate == nil || date.Before(*activeDate) {
-					activeDate = &date
-					activeDateKey = &dateStr
-				}
-			}
+	return &resolved
+}
+
+// activeRecording returns the historical override in recordings whose [EffectiveFrom,
+// EffectiveTo) window contains at, or nil if recordings is empty or none applies. A nil
+// EffectiveFrom/EffectiveTo bound is treated as unbounded on that side.
+func activeRecording(recordings map[string]taxRate, at time.Time) *taxRate {
+	for _, recording := range recordings {
+		recording := recording
+
+		if recording.EffectiveFrom != nil && at.Before(*recording.EffectiveFrom) {
+			continue
 		}
 
-		if activeDateKey != nil {
-			return Ptr(rate.PreviousRecordings[*activeDateKey]), nil
+		if recording.EffectiveTo != nil && !at.Before(*recording.EffectiveTo) {
+			continue
 		}
+
+		return &recording
 	}
 
-	return &rate, nil
+	return nil
 }
 
 func (t *Ctrl) getSalesTaxRates() (map[string]taxRate, error) {
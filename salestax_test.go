@@ -1,6 +1,7 @@
 package salestax
 
 import (
+	"context"
 	"strconv"
 	"testing"
 	"time"
@@ -8,17 +9,17 @@ import (
 
 func Test_GetSalesTax(t *testing.T) {
 	testCases := []struct {
-		originCountryCode  *string
-		regionalTaxEnabled bool
-		countryCode        string
-		stateCode          *string
-		taxNumber          *string
-		expectedResult     SalesTax
+		originCountryCode *string
+		ossRegistered     bool
+		countryCode       string
+		stateCode         *string
+		taxNumber         *string
+		expectedResult    SalesTax
 	}{
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: true,
-			countryCode:        "DE",
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     true,
+			countryCode:       "DE",
 			expectedResult: SalesTax{
 				Type:     "vat",
 				Rate:     0.19,
@@ -31,10 +32,10 @@ func Test_GetSalesTax(t *testing.T) {
 			},
 		},
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: true,
-			countryCode:        "DE",
-			taxNumber:          Ptr("DE000000000"),
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     true,
+			countryCode:       "DE",
+			taxNumber:         Ptr("DE000000000"),
 			expectedResult: SalesTax{
 				Type:     "vat",
 				Rate:     0.19,
@@ -47,9 +48,9 @@ func Test_GetSalesTax(t *testing.T) {
 			},
 		},
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: false,
-			countryCode:        "FR",
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     false,
+			countryCode:       "FR",
 			expectedResult: SalesTax{
 				Type:     "vat",
 				Rate:     0.19,
@@ -62,9 +63,9 @@ func Test_GetSalesTax(t *testing.T) {
 			},
 		},
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: true,
-			countryCode:        "FR",
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     true,
+			countryCode:       "FR",
 			expectedResult: SalesTax{
 				Type:     "vat",
 				Rate:     0.2,
@@ -77,10 +78,10 @@ func Test_GetSalesTax(t *testing.T) {
 			},
 		},
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: true,
-			countryCode:        "FR",
-			taxNumber:          Ptr("FR000000000"),
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     true,
+			countryCode:       "FR",
+			taxNumber:         Ptr("FR000000000"),
 			expectedResult: SalesTax{
 				Type:     "vat",
 				Rate:     0.2,
@@ -93,10 +94,10 @@ func Test_GetSalesTax(t *testing.T) {
 			},
 		},
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: true,
-			countryCode:        "CA",
-			stateCode:          Ptr("QC"),
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     true,
+			countryCode:       "CA",
+			stateCode:         Ptr("QC"),
 			expectedResult: SalesTax{
 				Type:     "gst+qst",
 				Rate:     0.14975,
@@ -109,11 +110,11 @@ func Test_GetSalesTax(t *testing.T) {
 			},
 		},
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: true,
-			countryCode:        "US",
-			stateCode:          Ptr("NY"),
-			taxNumber:          Ptr("0123456789"),
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     true,
+			countryCode:       "US",
+			stateCode:         Ptr("NY"),
+			taxNumber:         Ptr("0123456789"),
 			expectedResult: SalesTax{
 				Type:     "vat",
 				Rate:     0.04,
@@ -126,10 +127,10 @@ func Test_GetSalesTax(t *testing.T) {
 			},
 		},
 		{
-			originCountryCode:  Ptr("DE"),
-			regionalTaxEnabled: true,
-			countryCode:        "US",
-			stateCode:          Ptr("NY"),
+			originCountryCode: Ptr("DE"),
+			ossRegistered:     true,
+			countryCode:       "US",
+			stateCode:         Ptr("NY"),
 			expectedResult: SalesTax{
 				Type:     "vat",
 				Rate:     0.04,
@@ -145,11 +146,11 @@ func Test_GetSalesTax(t *testing.T) {
 
 	for _, tc := range testCases {
 		ctrl := &Ctrl{
-			OriginCountryCode:  tc.originCountryCode,
-			RegionalTaxEnabled: tc.regionalTaxEnabled,
+			OriginCountryCode: tc.originCountryCode,
+			ThresholdPolicy:   &ThresholdPolicy{OSSRegistered: tc.ossRegistered},
 		}
 
-		salesTax, err := ctrl.GetSalesTax(tc.countryCode, tc.stateCode, tc.taxNumber)
+		salesTax, err := ctrl.GetSalesTax(context.Background(), tc.countryCode, tc.stateCode, tc.taxNumber, nil)
 		if err != nil {
 			t.Errorf("got error: %s", err.Error())
 			return
@@ -219,7 +220,7 @@ func Test_getTaxExchangeStatus(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		exchangeStatus, exemptStatus, err := ctrl.getTaxExchangeStatus(tc.countryCode, tc.stateCode, tc.taxNumber)
+		exchangeStatus, exemptStatus, _, err := ctrl.getTaxExchangeStatus(context.Background(), tc.countryCode, tc.stateCode, tc.taxNumber)
 		if err != nil {
 			t.Errorf("got error: %s", err.Error())
 			return
@@ -400,3 +401,59 @@ func Test_getRegionCountries(t *testing.T) {
 		return
 	}
 }
+
+func Test_GetSalesTax_productClass(t *testing.T) {
+	ctrl := &Ctrl{OriginCountryCode: Ptr("DE")}
+
+	standard, err := ctrl.GetSalesTax(context.Background(), "DE", nil, nil, nil)
+	if err != nil {
+		t.Errorf("got error: %s", err.Error())
+		return
+	}
+	if standard.Rate != 0.19 {
+		t.Errorf("expected standard rate %f; got %f", 0.19, standard.Rate)
+		return
+	}
+
+	reduced, err := ctrl.GetSalesTax(context.Background(), "DE", nil, nil, Ptr(ProductClassReduced))
+	if err != nil {
+		t.Errorf("got error: %s", err.Error())
+		return
+	}
+	if reduced.Rate != 0.07 {
+		t.Errorf("expected reduced rate %f; got %f", 0.07, reduced.Rate)
+		return
+	}
+
+	unknown, err := ctrl.GetSalesTax(context.Background(), "DE", nil, nil, Ptr(ProductClass("unknown")))
+	if err != nil {
+		t.Errorf("got error: %s", err.Error())
+		return
+	}
+	if unknown.Rate != 0.19 {
+		t.Errorf("expected unknown product class to fall back to standard rate %f; got %f", 0.19, unknown.Rate)
+		return
+	}
+}
+
+func Test_ListProductClasses(t *testing.T) {
+	ctrl := &Ctrl{}
+
+	classes, err := ctrl.ListProductClasses("DE")
+	if err != nil {
+		t.Errorf("got error: %s", err.Error())
+		return
+	}
+
+	expected := []string{"ebook", "food", "hospitality", "reduced"}
+	if len(classes) != len(expected) {
+		t.Errorf("expected %v; got %v", expected, classes)
+		return
+	}
+	for i, class := range classes {
+		if class != expected[i] {
+			t.Errorf("expected %v; got %v", expected, classes)
+			return
+		}
+	}
+}
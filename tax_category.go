@@ -0,0 +1,93 @@
+package salestax
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// UNCL5305 tax category codes, as used by e-invoicing formats such as Peppol BIS,
+// FatturaPA, Romanian e-Factura and the Hungarian NAV schema.
+const (
+	taxCategoryStandard           = "S"  // standard-rated national supply
+	taxCategoryReduced            = "AA" // reduced-rated national supply
+	taxCategoryZero               = "Z"  // zero-rated national supply
+	taxCategoryExempt             = "E"  // exempt from tax
+	taxCategoryReverseCharge      = "AE" // VAT reverse charge
+	taxCategoryIntraCommunity     = "K"  // intra-community supply of goods/services
+	taxCategoryExport             = "G"  // export of goods outside the EU
+	taxCategoryOutsideScope       = "O"  // not subject to tax, e.g. an unrecognized jurisdiction
+	taxCategoryReverseChargeIntra = taxCategoryReverseCharge + "+" + taxCategoryIntraCommunity
+)
+
+const (
+	exemptionReasonExempt         = "VATEX-EU-79-C"
+	exemptionReasonIntraCommunity = "VATEX-EU-IC"
+)
+
+// computeTaxCategory derives the UNCL5305 category code (and, where applicable, the EN 16931
+// exemption reason code) from the same decision tree GetSalesTax already uses to resolve the
+// area and charge type:
+//   - an unrecognized country is out of scope entirely
+//   - a reduced/super-reduced or zero productClass on a national sale maps to AA or Z
+//   - an exempt productClass always maps to E
+//   - a national sale is otherwise standard-rated (S)
+//   - a regional (EU) sale under reverse charge is an intra-community supply (AE+K)
+//   - any worldwide sale is an export outside the EU (G), regardless of charge type, since
+//     TaxAreaWorldwide already means the origin and customer countries aren't in the same region
+func computeTaxCategory(area TaxArea, charge TaxCharge, productClass *ProductClass, countryKnown bool) (code string, reason string) {
+	if !countryKnown {
+		return taxCategoryOutsideScope, ""
+	}
+
+	if area == TaxAreaNational && productClass != nil {
+		switch *productClass {
+		case ProductClassZero:
+			return taxCategoryZero, ""
+		case ProductClassReduced, ProductClassSuperReduced:
+			return taxCategoryReduced, ""
+		case ProductClassExempt:
+			return taxCategoryExempt, exemptionReasonExempt
+		}
+	}
+
+	switch area {
+	case TaxAreaNational:
+		return taxCategoryStandard, ""
+	case TaxAreaRegional:
+		if charge.Reverse {
+			return taxCategoryReverseChargeIntra, exemptionReasonIntraCommunity
+		}
+
+		return taxCategoryStandard, ""
+	case TaxAreaWorldwide:
+		return taxCategoryExport, ""
+	}
+
+	return taxCategoryOutsideScope, ""
+}
+
+// isKnownCountry reports whether countryCode has a dedicated entry in the embedded sales tax
+// rate data, as opposed to silently falling back to defaultTaxRate.
+func (t *Ctrl) isKnownCountry(countryCode string) (bool, error) {
+	rates, err := t.getSalesTaxRates()
+	if err != nil {
+		return false, fmt.Errorf("failed to get sales tax rates: %w", err)
+	}
+
+	_, ok := rates[strings.ToUpper(countryCode)]
+
+	return ok, nil
+}
+
+// GetTaxCategory is a shortcut for callers that only need the UNCL5305 tax category code (and
+// its EN 16931 exemption reason, if any) for e-invoicing, without the numeric rate GetSalesTax
+// also computes.
+func (t *Ctrl) GetTaxCategory(ctx context.Context, countryCode string, stateCode *string, taxNumber *string, productClass *ProductClass) (code string, reason string, err error) {
+	salesTax, err := t.GetSalesTax(ctx, countryCode, stateCode, taxNumber, productClass)
+	if err != nil {
+		return "", "", err
+	}
+
+	return salesTax.CategoryCode, salesTax.CategoryReason, nil
+}
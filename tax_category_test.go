@@ -0,0 +1,135 @@
+package salestax
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_computeTaxCategory(t *testing.T) {
+	testCases := []struct {
+		name           string
+		area           TaxArea
+		charge         TaxCharge
+		productClass   *ProductClass
+		known          bool
+		expectedCode   string
+		expectedReason string
+	}{
+		{
+			name:         "unknown country is out of scope",
+			area:         TaxAreaNational,
+			charge:       TaxCharge{Direct: true},
+			known:        false,
+			expectedCode: taxCategoryOutsideScope,
+		},
+		{
+			name:         "national standard rate",
+			area:         TaxAreaNational,
+			charge:       TaxCharge{Direct: true},
+			known:        true,
+			expectedCode: taxCategoryStandard,
+		},
+		{
+			name:         "national reduced product class",
+			area:         TaxAreaNational,
+			charge:       TaxCharge{Direct: true},
+			productClass: Ptr(ProductClassReduced),
+			known:        true,
+			expectedCode: taxCategoryReduced,
+		},
+		{
+			name:         "national zero product class",
+			area:         TaxAreaNational,
+			charge:       TaxCharge{Direct: true},
+			productClass: Ptr(ProductClassZero),
+			known:        true,
+			expectedCode: taxCategoryZero,
+		},
+		{
+			name:           "national exempt product class",
+			area:           TaxAreaNational,
+			charge:         TaxCharge{},
+			productClass:   Ptr(ProductClassExempt),
+			known:          true,
+			expectedCode:   taxCategoryExempt,
+			expectedReason: exemptionReasonExempt,
+		},
+		{
+			name:           "regional reverse charge is an intra-community supply",
+			area:           TaxAreaRegional,
+			charge:         TaxCharge{Reverse: true},
+			known:          true,
+			expectedCode:   taxCategoryReverseChargeIntra,
+			expectedReason: exemptionReasonIntraCommunity,
+		},
+		{
+			name:         "regional direct charge",
+			area:         TaxAreaRegional,
+			charge:       TaxCharge{Direct: true},
+			known:        true,
+			expectedCode: taxCategoryStandard,
+		},
+		{
+			name:         "worldwide reverse charge is an export",
+			area:         TaxAreaWorldwide,
+			charge:       TaxCharge{Reverse: true},
+			known:        true,
+			expectedCode: taxCategoryExport,
+		},
+		{
+			name:         "worldwide direct charge is also an export",
+			area:         TaxAreaWorldwide,
+			charge:       TaxCharge{Direct: true},
+			known:        true,
+			expectedCode: taxCategoryExport,
+		},
+	}
+
+	for _, tc := range testCases {
+		code, reason := computeTaxCategory(tc.area, tc.charge, tc.productClass, tc.known)
+		if code != tc.expectedCode {
+			t.Errorf("%s: expected code %s; got %s", tc.name, tc.expectedCode, code)
+		}
+		if reason != tc.expectedReason {
+			t.Errorf("%s: expected reason %s; got %s", tc.name, tc.expectedReason, reason)
+		}
+	}
+}
+
+func Test_isKnownCountry(t *testing.T) {
+	ctrl := &Ctrl{}
+
+	known, err := ctrl.isKnownCountry("DE")
+	if err != nil {
+		t.Errorf("got error: %s", err.Error())
+		return
+	}
+	if !known {
+		t.Errorf("expected DE to be a known country")
+	}
+
+	unknown, err := ctrl.isKnownCountry("??")
+	if err != nil {
+		t.Errorf("got error: %s", err.Error())
+		return
+	}
+	if unknown {
+		t.Errorf("expected ?? not to be a known country")
+	}
+}
+
+func Test_GetTaxCategory(t *testing.T) {
+	ctrl := &Ctrl{OriginCountryCode: Ptr("DE"), ThresholdPolicy: &ThresholdPolicy{OSSRegistered: true}}
+
+	code, reason, err := ctrl.GetTaxCategory(context.Background(), "FR", nil, Ptr("FR000000000"), nil)
+	if err != nil {
+		t.Errorf("got error: %s", err.Error())
+		return
+	}
+	if code != taxCategoryReverseChargeIntra {
+		t.Errorf("expected code %s; got %s", taxCategoryReverseChargeIntra, code)
+	}
+	if reason != exemptionReasonIntraCommunity {
+		t.Errorf("expected reason %s; got %s", exemptionReasonIntraCommunity, reason)
+	}
+}
@@ -0,0 +1,190 @@
+package salestax
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_GetSalesTaxAt_germanyCovidCut(t *testing.T) {
+	ctrl := &Ctrl{OriginCountryCode: Ptr("DE")}
+
+	testCases := []struct {
+		name         string
+		at           time.Time
+		productClass *ProductClass
+		expectedRate float32
+	}{
+		{
+			name:         "standard rate before the COVID cut",
+			at:           time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.19,
+		},
+		{
+			name:         "standard rate during the COVID cut",
+			at:           time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.16,
+		},
+		{
+			name:         "reduced rate during the COVID cut",
+			at:           time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC),
+			productClass: Ptr(ProductClassReduced),
+			expectedRate: 0.05,
+		},
+		{
+			name:         "standard rate after the COVID cut ends",
+			at:           time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.19,
+		},
+	}
+
+	for _, tc := range testCases {
+		salesTax, err := ctrl.GetSalesTaxAt(context.Background(), "DE", nil, nil, tc.productClass, tc.at)
+		if err != nil {
+			t.Errorf("%s: got error: %s", tc.name, err.Error())
+			continue
+		}
+		if salesTax.Rate != tc.expectedRate {
+			t.Errorf("%s: expected rate %f; got %f", tc.name, tc.expectedRate, salesTax.Rate)
+		}
+	}
+}
+
+func Test_GetSalesTaxAt_ukHospitalityCut(t *testing.T) {
+	ctrl := &Ctrl{OriginCountryCode: Ptr("GB")}
+
+	testCases := []struct {
+		name         string
+		at           time.Time
+		expectedRate float32
+	}{
+		{
+			name:         "standard reduced rate before the cut",
+			at:           time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.05,
+		},
+		{
+			name:         "initial cut",
+			at:           time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.05,
+		},
+		{
+			name:         "stepped-up rate",
+			at:           time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.125,
+		},
+		{
+			name:         "back to the standard reduced rate",
+			at:           time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.05,
+		},
+	}
+
+	for _, tc := range testCases {
+		salesTax, err := ctrl.GetSalesTaxAt(context.Background(), "GB", nil, nil, Ptr(ProductClass("hospitality")), tc.at)
+		if err != nil {
+			t.Errorf("%s: got error: %s", tc.name, err.Error())
+			continue
+		}
+		if salesTax.Rate != tc.expectedRate {
+			t.Errorf("%s: expected rate %f; got %f", tc.name, tc.expectedRate, salesTax.Rate)
+		}
+	}
+}
+
+func Test_GetSalesTaxAt_stateRateHistory(t *testing.T) {
+	ctrl := &Ctrl{OriginCountryCode: Ptr("US")}
+
+	testCases := []struct {
+		name         string
+		at           time.Time
+		expectedRate float32
+	}{
+		{
+			name:         "NY rate before the 2021 change",
+			at:           time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.03,
+		},
+		{
+			name:         "NY rate after the 2021 change",
+			at:           time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			expectedRate: 0.04,
+		},
+	}
+
+	for _, tc := range testCases {
+		salesTax, err := ctrl.GetSalesTaxAt(context.Background(), "US", Ptr("NY"), nil, nil, tc.at)
+		if err != nil {
+			t.Errorf("%s: got error: %s", tc.name, err.Error())
+			continue
+		}
+		if salesTax.Rate != tc.expectedRate {
+			t.Errorf("%s: expected rate %f; got %f", tc.name, tc.expectedRate, salesTax.Rate)
+		}
+	}
+}
+
+// Test_hasTotalSalesTaxAt_stateRateHistory pins down that hasTotalSalesTaxAt resolves a state
+// rate's own history against at too, the same as the rate itself does, rather than always
+// reading the state's current top-level rate.
+func Test_hasTotalSalesTaxAt_stateRateHistory(t *testing.T) {
+	ctrl := &Ctrl{taxRates: map[string]taxRate{
+		"XX": {
+			TaxType: "none",
+			TaxRate: 0,
+			States: map[string]taxRate{
+				"YY": {
+					TaxType: "vat",
+					TaxRate: 0.05,
+					PreviousRecordings: map[string]taxRate{
+						"pre_levy": {
+							TaxType:     "none",
+							TaxRate:     0,
+							EffectiveTo: Ptr(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)),
+						},
+					},
+				},
+			},
+		},
+	}}
+
+	before, err := ctrl.hasTotalSalesTaxAt("XX", Ptr("YY"), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if before {
+		t.Errorf("expected no tax before the state levy took effect")
+	}
+
+	after, err := ctrl.hasTotalSalesTaxAt("XX", Ptr("YY"), time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if !after {
+		t.Errorf("expected tax to apply once the state levy took effect")
+	}
+}
+
+func Test_activeRecording(t *testing.T) {
+	recordings := map[string]taxRate{
+		"unbounded_past": {
+			TaxRate:     0.1,
+			EffectiveTo: Ptr(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		},
+		"windowed": {
+			TaxRate:       0.05,
+			EffectiveFrom: Ptr(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			EffectiveTo:   Ptr(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+
+	if got := activeRecording(recordings, time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)); got == nil || got.TaxRate != 0.1 {
+		t.Errorf("expected the unbounded-past recording to apply; got %+v", got)
+	}
+	if got := activeRecording(recordings, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)); got == nil || got.TaxRate != 0.05 {
+		t.Errorf("expected the windowed recording to apply; got %+v", got)
+	}
+	if got := activeRecording(recordings, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)); got != nil {
+		t.Errorf("expected no recording to apply after all windows close; got %+v", got)
+	}
+}
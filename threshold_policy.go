@@ -0,0 +1,167 @@
+package salestax
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// regionOSSKey is the RegistrationThresholds key for the combined EU-wide distance-selling
+// threshold (currently €10,000) that governs when a seller must start charging destination-
+// country VAT under the EU VAT One-Stop-Shop scheme, rather than their own country's VAT.
+const regionOSSKey = "EU"
+
+//go:embed res/registration_thresholds.json
+var registrationThresholdsData []byte
+
+// ThresholdPolicy tracks a seller's EU VAT OSS distance-selling position, replacing the old
+// RegionalTaxEnabled bool. Once OSSRegistered is true, or the running AnnualCrossBorderSales
+// total crosses the EU-wide threshold, GetSalesTax and CalculateTax switch from charging the
+// origin country's VAT on regional sales to charging the destination country's VAT via OSS,
+// without the caller having to track the threshold itself.
+type ThresholdPolicy struct {
+	// OSSRegistered marks the seller as voluntarily registered for the EU VAT One-Stop-Shop,
+	// so destination-country VAT applies to every regional sale regardless of the running total.
+	OSSRegistered bool
+	// IOSSRegistered marks the seller as registered for the Import One-Stop-Shop, under which
+	// import VAT on low-value consignments is collected at the point of sale rather than
+	// assessed at the border. It is surfaced for callers building customs declarations or
+	// e-invoices; GetSalesTax has no per-order amount to apply the €150 consignment check
+	// itself, so that check belongs with the caller or a future line-item-aware extension.
+	IOSSRegistered bool
+	// AnnualCrossBorderSales is the running total of net sales (in EUR) to each EU destination
+	// country so far this year, keyed by destination country code. Feed it with Ctrl.RecordSale.
+	AnnualCrossBorderSales map[string]decimal.Decimal
+	// RegistrationThresholds holds the EU-wide OSS threshold (key "EU") and, for callers that
+	// need them, per-country domestic small-business VAT registration thresholds (e.g. "DE":
+	// 22000 for the Kleinunternehmerregelung, "GB": 90000). Only the "EU" key is consulted by
+	// ossActive/WouldCrossThreshold: domestic small-business exemptions turn on the seller's
+	// total domestic turnover, not the destination-keyed cross-border totals this policy
+	// tracks, so applying them correctly is out of scope here and left to the caller. Defaults
+	// to the embedded registration thresholds data when nil.
+	RegistrationThresholds map[string]decimal.Decimal
+
+	mu sync.Mutex
+}
+
+func defaultRegistrationThresholds() (map[string]decimal.Decimal, error) {
+	var thresholds map[string]decimal.Decimal
+	if err := json.Unmarshal(registrationThresholdsData, &thresholds); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	return thresholds, nil
+}
+
+func (p *ThresholdPolicy) thresholds() (map[string]decimal.Decimal, error) {
+	if p.RegistrationThresholds != nil {
+		return p.RegistrationThresholds, nil
+	}
+
+	return defaultRegistrationThresholds()
+}
+
+// crossBorderTotal returns the combined running total across every destination country
+// recorded so far, since the OSS threshold applies EU-wide rather than per destination.
+func (p *ThresholdPolicy) crossBorderTotal() decimal.Decimal {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := decimal.Zero
+	for _, amount := range p.AnnualCrossBorderSales {
+		total = total.Add(amount)
+	}
+
+	return total
+}
+
+// ossActive reports whether destination-country VAT should be charged for a regional sale:
+// either because the seller is voluntarily OSS-registered, or because the EU-wide running
+// total has already crossed the threshold.
+func (p *ThresholdPolicy) ossActive() (bool, error) {
+	if p.OSSRegistered {
+		return true, nil
+	}
+
+	thresholds, err := p.thresholds()
+	if err != nil {
+		return false, err
+	}
+
+	threshold, ok := thresholds[regionOSSKey]
+	if !ok {
+		return false, nil
+	}
+
+	return p.crossBorderTotal().GreaterThanOrEqual(threshold), nil
+}
+
+// RecordSale adds netAmountEUR to the running cross-border sales total for destCountry, so
+// later calls to GetSalesTax and CalculateTax see an up-to-date OSS threshold position.
+func (p *ThresholdPolicy) RecordSale(destCountry string, netAmountEUR decimal.Decimal) {
+	destCountry = strings.ToUpper(destCountry)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.AnnualCrossBorderSales == nil {
+		p.AnnualCrossBorderSales = make(map[string]decimal.Decimal)
+	}
+
+	p.AnnualCrossBorderSales[destCountry] = p.AnnualCrossBorderSales[destCountry].Add(netAmountEUR)
+}
+
+// WouldCrossThreshold reports whether recording a sale of netAmountEUR would cross the
+// EU-wide OSS threshold, for warning UIs that want to flag the transition in advance. The
+// threshold is evaluated against the combined AnnualCrossBorderSales total, since the €10,000
+// OSS threshold applies EU-wide rather than per destination country; it does not take a
+// destination country, even though AnnualCrossBorderSales is keyed by one, for the same reason.
+func (p *ThresholdPolicy) WouldCrossThreshold(netAmountEUR decimal.Decimal) (bool, error) {
+	if p.OSSRegistered {
+		return false, nil
+	}
+
+	thresholds, err := p.thresholds()
+	if err != nil {
+		return false, err
+	}
+
+	threshold, ok := thresholds[regionOSSKey]
+	if !ok {
+		return false, nil
+	}
+
+	current := p.crossBorderTotal()
+	if current.GreaterThanOrEqual(threshold) {
+		return false, nil
+	}
+
+	return current.Add(netAmountEUR).GreaterThanOrEqual(threshold), nil
+}
+
+// thresholdPolicy returns t.ThresholdPolicy, lazily initializing it to a fresh policy (not yet
+// OSS-registered, no recorded sales) the first time it is needed, matching the zero-value
+// semantics the old RegionalTaxEnabled bool had.
+func (t *Ctrl) thresholdPolicy() *ThresholdPolicy {
+	if t.ThresholdPolicy == nil {
+		t.ThresholdPolicy = &ThresholdPolicy{}
+	}
+
+	return t.ThresholdPolicy
+}
+
+// RecordSale feeds netAmountEUR into the running EU cross-border sales total for destCountry,
+// so future GetSalesTax and CalculateTax calls reflect an up-to-date OSS threshold position.
+func (t *Ctrl) RecordSale(destCountry string, netAmountEUR decimal.Decimal) {
+	t.thresholdPolicy().RecordSale(destCountry, netAmountEUR)
+}
+
+// WouldCrossThreshold reports whether recording a sale of netAmountEUR would cross the
+// EU-wide OSS threshold, for warning UIs that want to flag the transition in advance.
+func (t *Ctrl) WouldCrossThreshold(netAmountEUR decimal.Decimal) (bool, error) {
+	return t.thresholdPolicy().WouldCrossThreshold(netAmountEUR)
+}
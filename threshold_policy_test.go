@@ -0,0 +1,108 @@
+package salestax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func Test_GetSalesTax_thresholdPolicy_switchesOnceThresholdCrossed(t *testing.T) {
+	ctrl := &Ctrl{OriginCountryCode: Ptr("DE")}
+
+	belowThreshold, err := ctrl.GetSalesTax(context.Background(), "FR", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if belowThreshold.Rate != 0.19 {
+		t.Errorf("expected origin-country rate 0.19 below the OSS threshold; got %f", belowThreshold.Rate)
+	}
+
+	ctrl.RecordSale("FR", decimal.NewFromInt(10000))
+
+	aboveThreshold, err := ctrl.GetSalesTax(context.Background(), "FR", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if aboveThreshold.Rate != 0.2 {
+		t.Errorf("expected destination-country rate 0.2 once the OSS threshold is crossed; got %f", aboveThreshold.Rate)
+	}
+}
+
+func Test_GetSalesTax_thresholdPolicy_ossRegisteredAlwaysUsesDestination(t *testing.T) {
+	ctrl := &Ctrl{
+		OriginCountryCode: Ptr("DE"),
+		ThresholdPolicy:   &ThresholdPolicy{OSSRegistered: true},
+	}
+
+	salesTax, err := ctrl.GetSalesTax(context.Background(), "FR", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if salesTax.Rate != 0.2 {
+		t.Errorf("expected destination-country rate 0.2 for an OSS-registered seller; got %f", salesTax.Rate)
+	}
+}
+
+func Test_RecordSale_and_WouldCrossThreshold(t *testing.T) {
+	policy := &ThresholdPolicy{}
+
+	would, err := policy.WouldCrossThreshold(decimal.NewFromInt(10000))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if !would {
+		t.Errorf("expected a 10000 EUR sale to cross the 10000 EUR EU-wide threshold")
+	}
+
+	policy.RecordSale("FR", decimal.NewFromInt(4000))
+	policy.RecordSale("BE", decimal.NewFromInt(4000))
+
+	would, err = policy.WouldCrossThreshold(decimal.NewFromInt(1000))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if would {
+		t.Errorf("expected the combined 9000 EUR total not to cross the threshold yet")
+	}
+
+	policy.RecordSale("NL", decimal.NewFromInt(3000))
+
+	active, err := policy.ossActive()
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if !active {
+		t.Errorf("expected OSS to be active once the combined total crosses 10000 EUR")
+	}
+}
+
+// Test_RegistrationThresholds_perCountryThresholdsAreCallerConsumedOnly pins down that the
+// embedded per-country domestic thresholds (e.g. DE's Kleinunternehmer threshold) are loaded
+// and exposed, but not consulted by ossActive/WouldCrossThreshold or by GetSalesTax: applying a
+// domestic small-business exemption depends on the seller's own total turnover, which this
+// policy doesn't track, so it's left to the caller.
+func Test_RegistrationThresholds_perCountryThresholdsAreCallerConsumedOnly(t *testing.T) {
+	policy := &ThresholdPolicy{}
+
+	thresholds, err := policy.thresholds()
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if _, ok := thresholds["DE"]; !ok {
+		t.Errorf("expected the embedded thresholds to include DE's Kleinunternehmer threshold")
+	}
+	if _, ok := thresholds["GB"]; !ok {
+		t.Errorf("expected the embedded thresholds to include GB's registration threshold")
+	}
+
+	ctrl := &Ctrl{OriginCountryCode: Ptr("DE"), ThresholdPolicy: policy}
+
+	salesTax, err := ctrl.GetSalesTax(context.Background(), "DE", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if salesTax.Rate != 0.19 {
+		t.Errorf("expected a national DE sale to charge the standard rate regardless of DE's loaded Kleinunternehmer threshold; got %f", salesTax.Rate)
+	}
+}
@@ -0,0 +1,6 @@
+package salestax
+
+// Ptr returns a pointer to the given value.
+func Ptr[T any](v T) *T {
+	return &v
+}
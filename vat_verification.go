@@ -0,0 +1,361 @@
+package salestax
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultVerificationCacheTTL is used when Ctrl.VATVerificationCacheTTL is zero.
+const defaultVerificationCacheTTL = 24 * time.Hour
+
+// VerificationResult is the outcome of verifying a buyer's VAT/GST number.
+type VerificationResult struct {
+	// Valid is true if the number was confirmed to belong to a registered business.
+	Valid bool
+	// RegisteredName is the business name on file for the number, if the registry returns one.
+	RegisteredName string
+	// RegisteredAddress is the business address on file for the number, if the registry returns one.
+	RegisteredAddress string
+	// ConsultationNumber is the registry-issued reference for this check, retained for the
+	// audit trail required by some member states (VIES calls this the requester consultation number).
+	ConsultationNumber string
+	// CheckedAt is when the verification was performed.
+	CheckedAt time.Time
+}
+
+// VATVerifier verifies a VAT/GST number against an authoritative registry.
+type VATVerifier interface {
+	Verify(ctx context.Context, countryCode string, number string) (*VerificationResult, error)
+}
+
+// cachedVerification is the sync.Map value type backing Ctrl.verificationCache.
+type cachedVerification struct {
+	result    *VerificationResult
+	expiresAt time.Time
+}
+
+// verifyTaxNumber runs the synchronous FormatValidator check before ever calling
+// t.VATVerifier, then consults and populates the verification cache around the network call.
+func (t *Ctrl) verifyTaxNumber(ctx context.Context, countryCode string, number string) (*VerificationResult, error) {
+	if !(FormatValidator{}).Validate(countryCode, number) {
+		return &VerificationResult{Valid: false, CheckedAt: currentTime()}, nil
+	}
+
+	cacheKey := countryCode + ":" + strings.ToUpper(number)
+
+	if cached, ok := t.verificationCache.Load(cacheKey); ok {
+		entry := cached.(cachedVerification)
+		if currentTime().Before(entry.expiresAt) {
+			return entry.result, nil
+		}
+
+		t.verificationCache.Delete(cacheKey)
+	}
+
+	result, err := t.VATVerifier.Verify(ctx, countryCode, number)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := t.VATVerificationCacheTTL
+	if ttl <= 0 {
+		ttl = defaultVerificationCacheTTL
+	}
+
+	t.verificationCache.Store(cacheKey, cachedVerification{result: result, expiresAt: currentTime().Add(ttl)})
+
+	return result, nil
+}
+
+// vatNumberPatterns are the structural patterns for the VAT number of each of the 27 EU
+// member states plus the UK, as published by the European Commission alongside VIES.
+// They only validate shape, not that the number is registered. Greece is keyed "EL", the
+// VAT prefix VIES and the EU use for Greece, rather than its ISO 3166-1 country code "GR";
+// normalizeVATCountryCode maps incoming "GR" country codes to "EL" before this map is consulted.
+var vatNumberPatterns = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^U\d{8}$`),
+	"BE": regexp.MustCompile(`^[01]\d{9}$`),
+	"BG": regexp.MustCompile(`^\d{9,10}$`),
+	"CY": regexp.MustCompile(`^\d{8}[A-Z]$`),
+	"CZ": regexp.MustCompile(`^\d{8,10}$`),
+	"DE": regexp.MustCompile(`^\d{9}$`),
+	"DK": regexp.MustCompile(`^\d{8}$`),
+	"EE": regexp.MustCompile(`^\d{9}$`),
+	"EL": regexp.MustCompile(`^\d{9}$`),
+	"ES": regexp.MustCompile(`^[A-Z0-9]\d{7}[A-Z0-9]$`),
+	"FI": regexp.MustCompile(`^\d{8}$`),
+	"FR": regexp.MustCompile(`^[A-HJ-NP-Z0-9]{2}\d{9}$`),
+	"HR": regexp.MustCompile(`^\d{11}$`),
+	"HU": regexp.MustCompile(`^\d{8}$`),
+	"IE": regexp.MustCompile(`^(\d{7}[A-Z]{1,2}|\d[A-Z]\d{5}[A-Z])$`),
+	"IT": regexp.MustCompile(`^\d{11}$`),
+	"LT": regexp.MustCompile(`^(\d{9}|\d{12})$`),
+	"LU": regexp.MustCompile(`^\d{8}$`),
+	"LV": regexp.MustCompile(`^\d{11}$`),
+	"MT": regexp.MustCompile(`^\d{8}$`),
+	"NL": regexp.MustCompile(`^\d{9}B\d{2}$`),
+	"PL": regexp.MustCompile(`^\d{10}$`),
+	"PT": regexp.MustCompile(`^\d{9}$`),
+	"RO": regexp.MustCompile(`^\d{2,10}$`),
+	"SE": regexp.MustCompile(`^\d{12}$`),
+	"SI": regexp.MustCompile(`^\d{8}$`),
+	"SK": regexp.MustCompile(`^\d{10}$`),
+	"GB": regexp.MustCompile(`^(\d{9}|\d{12}|GD\d{3}|HA\d{3})$`),
+}
+
+// FormatValidator runs a synchronous, per-country structural check on a VAT number, so that
+// obviously malformed numbers never reach a network verifier.
+type FormatValidator struct{}
+
+// Validate reports whether number matches the structural pattern for countryCode. A country
+// with no known pattern is treated as unverifiable by format alone and always passes, leaving
+// the decision to the configured VATVerifier.
+func (FormatValidator) Validate(countryCode string, number string) bool {
+	number = stripVATCountryPrefix(countryCode, number)
+
+	pattern, ok := vatNumberPatterns[normalizeVATCountryCode(countryCode)]
+	if !ok {
+		return true
+	}
+
+	return pattern.MatchString(strings.ToUpper(number))
+}
+
+// normalizeVATCountryCode upper-cases countryCode and maps it onto the country code VIES and
+// the EU VAT system actually expect, where that differs from the ISO 3166-1 country code: most
+// notably Greece, whose ISO code is "GR" but whose EU VAT prefix and VIES country code are "EL".
+func normalizeVATCountryCode(countryCode string) string {
+	countryCode = strings.ToUpper(countryCode)
+	if countryCode == "GR" {
+		return "EL"
+	}
+
+	return countryCode
+}
+
+// stripVATCountryPrefix removes a leading country-code prefix from number, e.g. "DE123456789"
+// for countryCode "DE" becomes "123456789". It tries both the ISO 3166-1 countryCode and, where
+// normalizeVATCountryCode maps it onto a different VAT prefix (Greece's "GR" to "EL"), that
+// prefix too, since suppliers commonly quote Greek numbers either way. Numbers without either
+// prefix are returned unchanged.
+func stripVATCountryPrefix(countryCode string, number string) string {
+	upperNumber := strings.ToUpper(number)
+
+	for _, prefix := range []string{strings.ToUpper(countryCode), normalizeVATCountryCode(countryCode)} {
+		if strings.HasPrefix(upperNumber, prefix) {
+			return number[len(prefix):]
+		}
+	}
+
+	return number
+}
+
+// ViesVerifier verifies EU VAT numbers against the European Commission's VIES SOAP service,
+// using the checkVatApprox operation so a requester consultation number is returned for the
+// audit trail some member states require businesses to retain.
+type ViesVerifier struct {
+	// RequesterCountryCode and RequesterNumber identify the caller's own VAT registration,
+	// as VIES requires for the checkVatApprox consultation-number workflow.
+	RequesterCountryCode string
+	RequesterNumber      string
+	// HTTPClient is used to call VIES. Defaults to http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// BaseURL overrides the VIES endpoint, for testing. Defaults to the production endpoint.
+	BaseURL string
+}
+
+const viesProductionURL = "https://ec.europa.eu/taxation_customs/vies/services/checkVatService"
+
+type viesCheckVatApproxEnvelope struct {
+	XMLName xml.Name               `xml:"soapenv:Envelope"`
+	XmlnsS  string                 `xml:"xmlns:soapenv,attr"`
+	XmlnsU  string                 `xml:"xmlns:urn,attr"`
+	Body    viesCheckVatApproxBody `xml:"soapenv:Body"`
+}
+
+type viesCheckVatApproxBody struct {
+	Request viesCheckVatApproxRequest `xml:"urn:checkVatApprox"`
+}
+
+type viesCheckVatApproxRequest struct {
+	CountryCode          string `xml:"urn:countryCode"`
+	VatNumber            string `xml:"urn:vatNumber"`
+	RequesterCountryCode string `xml:"urn:requesterCountryCode,omitempty"`
+	RequesterVatNumber   string `xml:"urn:requesterVatNumber,omitempty"`
+}
+
+type viesCheckVatApproxResponseEnvelope struct {
+	Body struct {
+		Response struct {
+			Valid             bool   `xml:"valid"`
+			Name              string `xml:"name"`
+			Address           string `xml:"address"`
+			RequestIdentifier string `xml:"requestIdentifier"`
+		} `xml:"checkVatApproxResponse"`
+	} `xml:"Body"`
+}
+
+// Verify implements VATVerifier by calling the VIES checkVatApprox SOAP operation.
+func (v ViesVerifier) Verify(ctx context.Context, countryCode string, number string) (*VerificationResult, error) {
+	number = stripVATCountryPrefix(countryCode, number)
+	countryCode = normalizeVATCountryCode(countryCode)
+
+	envelope := viesCheckVatApproxEnvelope{
+		XmlnsS: "http://schemas.xmlsoap.org/soap/envelope/",
+		XmlnsU: "urn:ec.europa.eu:taxud:vies:services:checkVat:types",
+		Body: viesCheckVatApproxBody{
+			Request: viesCheckVatApproxRequest{
+				CountryCode:          countryCode,
+				VatNumber:            number,
+				RequesterCountryCode: normalizeVATCountryCode(v.RequesterCountryCode),
+				RequesterVatNumber:   v.RequesterNumber,
+			},
+		},
+	}
+
+	body, err := xml.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build checkVatApprox request: %w", err)
+	}
+
+	baseURL := v.BaseURL
+	if baseURL == "" {
+		baseURL = viesProductionURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build VIES request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", "")
+
+	client := v.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call VIES: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VIES response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("VIES returned status %d", resp.StatusCode)
+	}
+
+	var parsed viesCheckVatApproxResponseEnvelope
+	if err := xml.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse VIES response: %w", err)
+	}
+
+	return &VerificationResult{
+		Valid:              parsed.Body.Response.Valid,
+		RegisteredName:     parsed.Body.Response.Name,
+		RegisteredAddress:  parsed.Body.Response.Address,
+		ConsultationNumber: parsed.Body.Response.RequestIdentifier,
+		CheckedAt:          currentTime(),
+	}, nil
+}
+
+// UKHMRCVerifier verifies UK VAT numbers against HMRC's VAT number check REST API.
+type UKHMRCVerifier struct {
+	// RequesterVRN is the caller's own UK VAT registration number, sent to HMRC so the
+	// lookup is attributed for the audit trail, matching the checkVatApprox pattern for VIES.
+	RequesterVRN string
+	// HTTPClient is used to call HMRC. Defaults to http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// BaseURL overrides the HMRC endpoint, for testing. Defaults to the production endpoint.
+	BaseURL string
+}
+
+const hmrcProductionURL = "https://api.service.hmrc.gov.uk"
+
+type hmrcLookupResponse struct {
+	Target struct {
+		Name    string `json:"name"`
+		Address struct {
+			Line1       string `json:"line1"`
+			Line2       string `json:"line2"`
+			Line3       string `json:"line3"`
+			PostCode    string `json:"postcode"`
+			CountryCode string `json:"countryCode"`
+		} `json:"address"`
+		VATNumber string `json:"vatNumber"`
+	} `json:"target"`
+	ConsultationNumber string `json:"consultationNumber"`
+}
+
+// Verify implements VATVerifier by calling HMRC's /organisations/vat/check-vat-number/lookup endpoint.
+func (v UKHMRCVerifier) Verify(ctx context.Context, countryCode string, number string) (*VerificationResult, error) {
+	vrn := stripVATCountryPrefix(countryCode, number)
+
+	baseURL := v.BaseURL
+	if baseURL == "" {
+		baseURL = hmrcProductionURL
+	}
+
+	url := fmt.Sprintf("%s/organisations/vat/check-vat-number/lookup/%s", baseURL, vrn)
+	if v.RequesterVRN != "" {
+		url = fmt.Sprintf("%s/%s", url, v.RequesterVRN)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build HMRC request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.hmrc.1.0+json")
+
+	client := v.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call HMRC: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &VerificationResult{Valid: false, CheckedAt: currentTime()}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HMRC returned status %d", resp.StatusCode)
+	}
+
+	var parsed hmrcLookupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse HMRC response: %w", err)
+	}
+
+	address := strings.TrimSpace(strings.Join([]string{
+		parsed.Target.Address.Line1,
+		parsed.Target.Address.Line2,
+		parsed.Target.Address.Line3,
+		parsed.Target.Address.PostCode,
+	}, ", "))
+
+	return &VerificationResult{
+		Valid:              true,
+		RegisteredName:     parsed.Target.Name,
+		RegisteredAddress:  address,
+		ConsultationNumber: parsed.ConsultationNumber,
+		CheckedAt:          currentTime(),
+	}, nil
+}
@@ -0,0 +1,254 @@
+package salestax
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_FormatValidator_Validate(t *testing.T) {
+	testCases := []struct {
+		countryCode string
+		number      string
+		expected    bool
+	}{
+		{countryCode: "DE", number: "DE123456789", expected: true},
+		{countryCode: "DE", number: "DE12345", expected: false},
+		{countryCode: "FR", number: "FR12345678901", expected: true},
+		{countryCode: "FR", number: "FR1234", expected: false},
+		{countryCode: "GB", number: "GB123456789", expected: true},
+		{countryCode: "GB", number: "GBGD123", expected: true},
+		{countryCode: "GR", number: "EL123456789", expected: true},
+		{countryCode: "GR", number: "GR123456789", expected: true},
+		{countryCode: "GR", number: "EL1234", expected: false},
+		{countryCode: "US", number: "anything", expected: true},
+	}
+
+	validator := FormatValidator{}
+
+	for _, tc := range testCases {
+		if got := validator.Validate(tc.countryCode, tc.number); got != tc.expected {
+			t.Errorf("%s %s: expected %t; got %t", tc.countryCode, tc.number, tc.expected, got)
+		}
+	}
+}
+
+type stubVerifier struct {
+	result *VerificationResult
+	calls  int
+}
+
+func (s *stubVerifier) Verify(_ context.Context, _ string, _ string) (*VerificationResult, error) {
+	s.calls++
+	return s.result, nil
+}
+
+func Test_GetSalesTax_withVATVerifier(t *testing.T) {
+	verifier := &stubVerifier{result: &VerificationResult{Valid: true, RegisteredName: "ACME SARL"}}
+
+	ctrl := &Ctrl{
+		OriginCountryCode: Ptr("DE"),
+		ThresholdPolicy:   &ThresholdPolicy{OSSRegistered: true},
+		VATVerifier:       verifier,
+	}
+
+	salesTax, err := ctrl.GetSalesTax(context.Background(), "FR", nil, Ptr("FR12345678901"), nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if salesTax.Exchange != TaxExchangeBusiness {
+		t.Errorf("expected business exchange; got %s", salesTax.Exchange)
+	}
+	if !salesTax.Charge.Reverse {
+		t.Errorf("expected reverse charge once the VAT number is verified")
+	}
+	if salesTax.TaxNumberVerification == nil || !salesTax.TaxNumberVerification.Valid {
+		t.Errorf("expected a verified verification result; got %+v", salesTax.TaxNumberVerification)
+	}
+
+	// second call should hit the cache rather than the verifier again
+	if _, err := ctrl.GetSalesTax(context.Background(), "FR", nil, Ptr("FR12345678901"), nil); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if verifier.calls != 1 {
+		t.Errorf("expected the verifier to be called once due to caching; got %d calls", verifier.calls)
+	}
+}
+
+func Test_GetSalesTax_withVATVerifier_invalidNumber(t *testing.T) {
+	verifier := &stubVerifier{result: &VerificationResult{Valid: false}}
+
+	ctrl := &Ctrl{
+		OriginCountryCode: Ptr("DE"),
+		ThresholdPolicy:   &ThresholdPolicy{OSSRegistered: true},
+		VATVerifier:       verifier,
+	}
+
+	salesTax, err := ctrl.GetSalesTax(context.Background(), "FR", nil, Ptr("FR12345678901"), nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if salesTax.Exchange != TaxExchangeConsumer {
+		t.Errorf("expected consumer exchange for an invalid VAT number; got %s", salesTax.Exchange)
+	}
+	if salesTax.Charge.Reverse {
+		t.Errorf("did not expect reverse charge for an invalid VAT number")
+	}
+	if salesTax.TaxNumberVerification == nil || salesTax.TaxNumberVerification.Valid {
+		t.Errorf("expected an invalid verification result; got %+v", salesTax.TaxNumberVerification)
+	}
+}
+
+func Test_GetSalesTax_withVATVerifier_malformedNumberNeverCallsVerifier(t *testing.T) {
+	verifier := &stubVerifier{result: &VerificationResult{Valid: true}}
+
+	ctrl := &Ctrl{
+		OriginCountryCode: Ptr("DE"),
+		ThresholdPolicy:   &ThresholdPolicy{OSSRegistered: true},
+		VATVerifier:       verifier,
+	}
+
+	salesTax, err := ctrl.GetSalesTax(context.Background(), "FR", nil, Ptr("FR1"), nil)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if salesTax.Exchange != TaxExchangeConsumer {
+		t.Errorf("expected consumer exchange for a malformed VAT number; got %s", salesTax.Exchange)
+	}
+	if verifier.calls != 0 {
+		t.Errorf("expected the verifier never to be called for a malformed number; got %d calls", verifier.calls)
+	}
+}
+
+func Test_ViesVerifier_Verify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <checkVatApproxResponse>
+      <valid>true</valid>
+      <name>ACME SARL</name>
+      <address>1 Rue de Paris</address>
+      <requestIdentifier>WAPPROX123</requestIdentifier>
+    </checkVatApproxResponse>
+  </soap:Body>
+</soap:Envelope>`))
+	}))
+	defer server.Close()
+
+	verifier := ViesVerifier{
+		RequesterCountryCode: "DE",
+		RequesterNumber:      "123456789",
+		BaseURL:              server.URL,
+	}
+
+	result, err := verifier.Verify(context.Background(), "FR", "FR12345678901")
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if !result.Valid {
+		t.Errorf("expected a valid result")
+	}
+	if result.RegisteredName != "ACME SARL" {
+		t.Errorf("expected registered name %q; got %q", "ACME SARL", result.RegisteredName)
+	}
+	if result.ConsultationNumber != "WAPPROX123" {
+		t.Errorf("expected consultation number %q; got %q", "WAPPROX123", result.ConsultationNumber)
+	}
+}
+
+func Test_ViesVerifier_Verify_greeceUsesELCountryCode(t *testing.T) {
+	var sentCountryCode, sentRequesterCountryCode string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sentCountryCode = extractSOAPTag(string(body), "urn:countryCode")
+		sentRequesterCountryCode = extractSOAPTag(string(body), "urn:requesterCountryCode")
+
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <checkVatApproxResponse>
+      <valid>true</valid>
+    </checkVatApproxResponse>
+  </soap:Body>
+</soap:Envelope>`))
+	}))
+	defer server.Close()
+
+	verifier := ViesVerifier{BaseURL: server.URL, RequesterCountryCode: "GR"}
+
+	if _, err := verifier.Verify(context.Background(), "GR", "EL123456789"); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if sentCountryCode != "EL" {
+		t.Errorf("expected VIES to be sent country code %q for Greece; got %q", "EL", sentCountryCode)
+	}
+	if sentRequesterCountryCode != "EL" {
+		t.Errorf("expected VIES to be sent requester country code %q for a Greek requester; got %q", "EL", sentRequesterCountryCode)
+	}
+}
+
+func extractSOAPTag(body string, tagName string) string {
+	tag := "<" + tagName + ">"
+	start := strings.Index(body, tag)
+	if start == -1 {
+		return ""
+	}
+	start += len(tag)
+	end := strings.Index(body[start:], "<")
+	if end == -1 {
+		return ""
+	}
+
+	return body[start : start+end]
+}
+
+func Test_UKHMRCVerifier_Verify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"target": {"name": "ACME LTD", "vatNumber": "123456789", "address": {"line1": "1 Downing St", "postcode": "SW1A 2AA"}},
+			"consultationNumber": "12345"
+		}`))
+	}))
+	defer server.Close()
+
+	verifier := UKHMRCVerifier{BaseURL: server.URL}
+
+	result, err := verifier.Verify(context.Background(), "GB", "GB123456789")
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if !result.Valid {
+		t.Errorf("expected a valid result")
+	}
+	if result.RegisteredName != "ACME LTD" {
+		t.Errorf("expected registered name %q; got %q", "ACME LTD", result.RegisteredName)
+	}
+	if result.ConsultationNumber != "12345" {
+		t.Errorf("expected consultation number %q; got %q", "12345", result.ConsultationNumber)
+	}
+}
+
+func Test_UKHMRCVerifier_Verify_notFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	verifier := UKHMRCVerifier{BaseURL: server.URL}
+
+	result, err := verifier.Verify(context.Background(), "GB", "GB999999999")
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if result.Valid {
+		t.Errorf("expected an invalid result for a 404 response")
+	}
+}